@@ -10,26 +10,68 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/portainer/agent/logger"
+	"github.com/portainer/agent/metrics"
 )
 
 const largeClientTimeout = 1 * time.Hour
 
-func ImagePull(refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+var log = logger.New("docker")
+
+// callDuration is the agent_docker_call_duration_seconds histogram used to
+// time every exported helper in this package, set via SetMetrics at agent
+// startup. It stays nil (and every timing call becomes a no-op) when no
+// Metrics was configured.
+var callDuration *metrics.HistogramVec
+
+// SetMetrics wires this package's Docker call instrumentation into m. It
+// must be called before any of the exported helpers below for their
+// durations to be recorded.
+func SetMetrics(m *metrics.Metrics) {
+	if m == nil {
+		return
+	}
+
+	callDuration = m.DockerCallDuration
+}
+
+// observeCallDuration times fn and, if SetMetrics was called, records the
+// duration against agent_docker_call_duration_seconds for the named call.
+func observeCallDuration(call string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	if callDuration != nil {
+		callDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+	}
+
+	return err
+}
+
+func ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
 	var err error
 	var reader io.ReadCloser
 
-	err = withCli(func(cli *client.Client) error {
-		cli.HTTPClient().Timeout = largeClientTimeout
+	log.Debug().Str("ref", refStr).Msg("pulling image")
 
-		reader, err = cli.ImagePull(context.Background(), refStr, options)
+	err = observeCallDuration("ImagePull", func() error {
+		return withCli(func(cli *client.Client) error {
+			cli.HTTPClient().Timeout = largeClientTimeout
 
-		return err
+			reader, err = cli.ImagePull(ctx, refStr, options)
+
+			return err
+		})
 	})
+	if err != nil {
+		log.Error().Str("ref", refStr).Err(err).Msg("image pull failed")
+	}
 
 	return reader, err
 }
 
 func ContainerCreate(
+	ctx context.Context,
 	config *container.Config,
 	hostConfig *container.HostConfig,
 	networkingConfig *network.NetworkingConfig,
@@ -39,55 +81,69 @@ func ContainerCreate(
 	var err error
 	var createResponse container.CreateResponse
 
-	err = withCli(func(cli *client.Client) error {
-		cli.HTTPClient().Timeout = largeClientTimeout
+	err = observeCallDuration("ContainerCreate", func() error {
+		return withCli(func(cli *client.Client) error {
+			cli.HTTPClient().Timeout = largeClientTimeout
 
-		createResponse, err = cli.ContainerCreate(context.Background(), config, hostConfig, networkingConfig, platform, containerName)
+			createResponse, err = cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
 
-		return err
+			return err
+		})
 	})
 
 	return createResponse, err
 }
 
-func ContainerStart(name string, opts container.StartOptions) error {
-	return withCli(func(cli *client.Client) error {
-		return cli.ContainerStart(context.Background(), name, opts)
+func ContainerStart(ctx context.Context, name string, opts container.StartOptions) error {
+	return observeCallDuration("ContainerStart", func() error {
+		return withCli(func(cli *client.Client) error {
+			return cli.ContainerStart(ctx, name, opts)
+		})
 	})
 }
 
-func ContainerRestart(name string) error {
-	return withCli(func(cli *client.Client) error {
-		return cli.ContainerRestart(context.Background(), name, container.StopOptions{})
+func ContainerRestart(ctx context.Context, name string) error {
+	return observeCallDuration("ContainerRestart", func() error {
+		return withCli(func(cli *client.Client) error {
+			return cli.ContainerRestart(ctx, name, container.StopOptions{})
+		})
 	})
 }
 
-func ContainerStop(name string) error {
-	return withCli(func(cli *client.Client) error {
-		return cli.ContainerStop(context.Background(), name, container.StopOptions{})
+func ContainerStop(ctx context.Context, name string) error {
+	return observeCallDuration("ContainerStop", func() error {
+		return withCli(func(cli *client.Client) error {
+			return cli.ContainerStop(ctx, name, container.StopOptions{})
+		})
 	})
 }
 
-func ContainerKill(name string) error {
-	return withCli(func(cli *client.Client) error {
-		return cli.ContainerKill(context.Background(), name, "KILL")
+func ContainerKill(ctx context.Context, name string) error {
+	return observeCallDuration("ContainerKill", func() error {
+		return withCli(func(cli *client.Client) error {
+			return cli.ContainerKill(ctx, name, "KILL")
+		})
 	})
 }
 
-func ContainerDelete(name string, opts container.RemoveOptions) error {
-	return withCli(func(cli *client.Client) error {
-		return cli.ContainerRemove(context.Background(), name, opts)
+func ContainerDelete(ctx context.Context, name string, opts container.RemoveOptions) error {
+	return observeCallDuration("ContainerDelete", func() error {
+		return withCli(func(cli *client.Client) error {
+			return cli.ContainerRemove(ctx, name, opts)
+		})
 	})
 }
 
-func ContainerWait(name string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+func ContainerWait(ctx context.Context, name string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
 	var statusCh <-chan container.WaitResponse
 	var errCh <-chan error
 
+	start := time.Now()
+
 	if err := withCli(func(cli *client.Client) error {
 		cli.HTTPClient().Timeout = largeClientTimeout
 
-		statusCh, errCh = cli.ContainerWait(context.Background(), name, condition)
+		statusCh, errCh = cli.ContainerWait(ctx, name, condition)
 
 		return nil
 	}); err != nil {
@@ -97,5 +153,9 @@ func ContainerWait(name string, condition container.WaitCondition) (<-chan conta
 		go func() { ch <- err }()
 	}
 
+	if callDuration != nil {
+		callDuration.WithLabelValues("ContainerWait").Observe(time.Since(start).Seconds())
+	}
+
 	return statusCh, errCh
 }
@@ -0,0 +1,226 @@
+package edge
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	// backoffMultiplier is applied once per consecutive poll failure, up to
+	// backoffMaxMultiplier, so the effective poll interval grows
+	// baseline, 2x, 4x, 8x, ... away from the configured/server-driven
+	// baseline instead of hammering an unreachable Portainer instance.
+	backoffMultiplier    = 2.0
+	backoffMaxMultiplier = 10.0
+	backoffMaxInterval   = 5 * time.Minute
+	// backoffJitterFraction spreads retries by up to ±20% so a fleet of
+	// agents that lost connectivity to the same Portainer instance at the
+	// same time doesn't reconnect in lockstep.
+	backoffJitterFraction = 0.2
+
+	// authFailureCircuitThreshold is the length of the consecutive-failure
+	// streak at which an authentication failure opens the circuit: once the
+	// streak reaches it and the latest failure is an auth failure, the poll
+	// loop stops polling altogether for circuitBreakerCooldown instead of
+	// continuing to back off - a bad edge key will not fix itself by waiting
+	// longer. The streak counts failures of every error class; it is not
+	// tracked per class.
+	authFailureCircuitThreshold = 3
+	circuitBreakerCooldown      = 10 * time.Minute
+)
+
+// errorClass buckets a poll failure so the backoff/circuit-breaker policy
+// can react differently to a flaky network than to a persistent 401.
+type errorClass string
+
+const (
+	errorClassNetwork errorClass = "network"
+	errorClassAuth    errorClass = "auth"
+	errorClassServer  errorClass = "5xx"
+	errorClassOther   errorClass = "other"
+)
+
+// classifyPollError inspects the error returned by
+// portainerClient.GetEnvironmentStatus and assigns it an errorClass. The
+// portainer client currently reports failures as plain errors rather than a
+// typed error hierarchy, so this relies on substring matching; it errs on
+// the side of errorClassOther when unsure.
+func classifyPollError(err error) errorClass {
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case strings.Contains(msg, "401") || strings.Contains(msg, "unauthorized") || strings.Contains(msg, "invalid edge key"):
+		return errorClassAuth
+	case strings.Contains(msg, "500") || strings.Contains(msg, "502") || strings.Contains(msg, "503") || strings.Contains(msg, "504"):
+		return errorClassServer
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host") || strings.Contains(msg, "eof"):
+		return errorClassNetwork
+	default:
+		return errorClassOther
+	}
+}
+
+// PollStatus is a snapshot of the poll loop's backoff/circuit-breaker
+// state, exposed to operators via the /status handler so they can see why
+// an agent stopped checking in.
+type PollStatus struct {
+	BaselineIntervalSeconds float64    `json:"baselineIntervalSeconds"`
+	CurrentIntervalSeconds  float64    `json:"currentIntervalSeconds"`
+	ConsecutiveFailures     int        `json:"consecutiveFailures"`
+	CircuitOpen             bool       `json:"circuitOpen"`
+	CircuitOpenUntil        *time.Time `json:"circuitOpenUntil,omitempty"`
+	LastError               string     `json:"lastError,omitempty"`
+	LastErrorClass          string     `json:"lastErrorClass,omitempty"`
+}
+
+// Status returns a snapshot of the poll loop's current backoff and
+// circuit-breaker state.
+func (service *PollService) Status() PollStatus {
+	service.statusMu.Lock()
+	defer service.statusMu.Unlock()
+
+	status := PollStatus{
+		BaselineIntervalSeconds: service.pollIntervalInSeconds,
+		CurrentIntervalSeconds:  computeBackoffInterval(service.pollIntervalInSeconds, service.consecutiveFailures).Seconds(),
+		ConsecutiveFailures:     service.consecutiveFailures,
+		CircuitOpen:             !service.circuitOpenUntil.IsZero(),
+	}
+
+	if !service.circuitOpenUntil.IsZero() {
+		openUntil := service.circuitOpenUntil
+		status.CircuitOpenUntil = &openUntil
+	}
+
+	if service.lastError != nil {
+		status.LastError = service.lastError.Error()
+		status.LastErrorClass = string(service.lastErrorClass)
+	}
+
+	return status
+}
+
+// recordPollResult updates the backoff/circuit-breaker state following a
+// poll attempt. A nil err resets the failure streak back to the baseline
+// interval.
+func (service *PollService) recordPollResult(err error) {
+	service.statusMu.Lock()
+	defer service.statusMu.Unlock()
+
+	if err == nil {
+		if service.consecutiveFailures > 0 {
+			service.logger.Info().Msg("Portainer poll recovered, resetting backoff to baseline interval")
+		}
+		service.consecutiveFailures = 0
+		service.lastError = nil
+		service.lastErrorClass = ""
+		service.circuitOpenUntil = time.Time{}
+		return
+	}
+
+	class := classifyPollError(err)
+	service.lastError = err
+	service.lastErrorClass = class
+	service.consecutiveFailures++
+
+	if class == errorClassAuth && service.consecutiveFailures >= authFailureCircuitThreshold && service.circuitOpenUntil.IsZero() {
+		service.circuitOpenUntil = time.Now().Add(circuitBreakerCooldown)
+		service.logger.Error().
+			Int("consecutive_failures", service.consecutiveFailures).
+			Dur("cooldown", circuitBreakerCooldown).
+			Msg("repeated authentication failures, opening circuit breaker and pausing polling")
+	}
+}
+
+// circuitOpen reports whether the circuit breaker is currently open. Once
+// the cooldown has elapsed it closes the circuit and resets the failure
+// streak so polling resumes at the baseline interval.
+func (service *PollService) circuitOpen() bool {
+	service.statusMu.Lock()
+	defer service.statusMu.Unlock()
+
+	if service.circuitOpenUntil.IsZero() {
+		return false
+	}
+
+	if time.Now().Before(service.circuitOpenUntil) {
+		return true
+	}
+
+	service.logger.Info().Msg("circuit breaker cooldown elapsed, resuming polling")
+	service.circuitOpenUntil = time.Time{}
+	service.consecutiveFailures = 0
+	return false
+}
+
+// maybeUpdatePollInterval applies a new server-driven checkin interval as
+// the backoff baseline, if it is positive and different from the current
+// one. It holds statusMu for the read-compare-write so it can't race with
+// Status()/nextPollInterval() reading pollIntervalInSeconds concurrently
+// from the /status handler goroutine.
+func (service *PollService) maybeUpdatePollInterval(checkinIntervalSeconds float64) {
+	if checkinIntervalSeconds <= 0 {
+		return
+	}
+
+	service.statusMu.Lock()
+	oldInterval := service.pollIntervalInSeconds
+	changed := checkinIntervalSeconds != oldInterval
+	if changed {
+		service.pollIntervalInSeconds = checkinIntervalSeconds
+	}
+	service.statusMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	service.logger.Debug().
+		Float64("old_interval", oldInterval).
+		Float64("new_interval", checkinIntervalSeconds).
+		Msg("updating poll interval")
+	service.portainerClient.SetTimeout(time.Duration(checkinIntervalSeconds) * time.Second)
+}
+
+// nextPollInterval returns how long the poll loop should wait before the
+// next attempt, applying backoff for consecutive failures (on top of the
+// current baseline, which the server's CheckinInterval may have updated)
+// and jitter to avoid a thundering herd across a fleet of agents.
+func (service *PollService) nextPollInterval() time.Duration {
+	service.statusMu.Lock()
+	baseline := service.pollIntervalInSeconds
+	failures := service.consecutiveFailures
+	service.statusMu.Unlock()
+
+	interval := computeBackoffInterval(baseline, failures)
+	if failures == 0 {
+		return interval
+	}
+
+	return applyJitter(interval)
+}
+
+func computeBackoffInterval(baselineSeconds float64, consecutiveFailures int) time.Duration {
+	if consecutiveFailures == 0 {
+		return time.Duration(baselineSeconds * float64(time.Second))
+	}
+
+	multiplier := math.Pow(backoffMultiplier, float64(consecutiveFailures))
+	if multiplier > backoffMaxMultiplier {
+		multiplier = backoffMaxMultiplier
+	}
+
+	interval := time.Duration(baselineSeconds * multiplier * float64(time.Second))
+	if interval > backoffMaxInterval {
+		interval = backoffMaxInterval
+	}
+
+	return interval
+}
+
+func applyJitter(d time.Duration) time.Duration {
+	jitter := float64(d) * backoffJitterFraction
+	delta := (rand.Float64()*2 - 1) * jitter
+	return d + time.Duration(delta)
+}
@@ -0,0 +1,60 @@
+package edge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyPollError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want errorClass
+	}{
+		{errors.New("401 Unauthorized"), errorClassAuth},
+		{errors.New("invalid edge key"), errorClassAuth},
+		{errors.New("502 Bad Gateway"), errorClassServer},
+		{errors.New("dial tcp: connection refused"), errorClassNetwork},
+		{errors.New("context deadline exceeded: timeout"), errorClassNetwork},
+		{errors.New("something unexpected"), errorClassOther},
+	}
+
+	for _, c := range cases {
+		if got := classifyPollError(c.err); got != c.want {
+			t.Errorf("classifyPollError(%q) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestComputeBackoffInterval(t *testing.T) {
+	baseline := 5.0
+
+	if got := computeBackoffInterval(baseline, 0); got != 5*time.Second {
+		t.Errorf("expected no backoff at 0 failures, got %s", got)
+	}
+
+	if got := computeBackoffInterval(baseline, 1); got != 10*time.Second {
+		t.Errorf("expected 2x backoff at 1 failure, got %s", got)
+	}
+
+	if got := computeBackoffInterval(baseline, 2); got != 20*time.Second {
+		t.Errorf("expected 4x backoff at 2 failures, got %s", got)
+	}
+
+	if got := computeBackoffInterval(baseline, 20); got != 50*time.Second {
+		t.Errorf("expected backoff to be capped at %vx the baseline, got %s", backoffMaxMultiplier, got)
+	}
+}
+
+func TestApplyJitterStaysWithinBounds(t *testing.T) {
+	base := 100 * time.Second
+
+	for i := 0; i < 100; i++ {
+		jittered := applyJitter(base)
+		lower := time.Duration(float64(base) * (1 - backoffJitterFraction))
+		upper := time.Duration(float64(base) * (1 + backoffJitterFraction))
+		if jittered < lower || jittered > upper {
+			t.Fatalf("jittered duration %s out of bounds [%s, %s]", jittered, lower, upper)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/portainer/agent/internal/runtime"
+	"github.com/portainer/agent/logger"
+)
+
+var log = logger.New("edge.client.stream")
+
+// pingHeartbeat is the cadence at which Portainer sends an "event: ping"
+// frame on an otherwise idle status stream so SSEClient can tell a quiet
+// connection from a dead one.
+const pingHeartbeat = 15 * time.Second
+
+// StatusUpdate is a single Server-Sent Event pushed by Portainer over the
+// long-lived connection opened by SSEClient.Stream. Event identifies what
+// changed ("status", "schedule", "stack", "tunnel"); Data carries the
+// associated JSON payload undecoded, so callers can unmarshal it into
+// whatever shape they expect for that event type.
+type StatusUpdate struct {
+	Event string
+	Data  []byte
+}
+
+// SSEClient opens a Server-Sent Events connection to Portainer to receive
+// push notifications of status changes, new schedules, stack updates and
+// tunnel-required events, so the agent doesn't have to short-poll for them.
+type SSEClient struct {
+	httpClient *http.Client
+	serverURL  string
+	edgeID     string
+}
+
+// NewSSEClient returns a pointer to a new SSEClient.
+func NewSSEClient(serverURL, edgeID string, httpClient *http.Client) *SSEClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	return &SSEClient{
+		httpClient: httpClient,
+		serverURL:  serverURL,
+		edgeID:     edgeID,
+	}
+}
+
+// Stream opens the SSE connection and returns a channel of StatusUpdate.
+// The channel is closed when ctx is cancelled, the server closes the
+// connection, or no event (including a ping heartbeat) is seen for longer
+// than twice pingHeartbeat; callers should fall back to short-polling in
+// those cases.
+func (c *SSEClient) Stream(ctx context.Context) (<-chan StatusUpdate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.serverURL+"/api/endpoints/edge/"+c.edgeID+"/status/stream", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d opening status stream", resp.StatusCode)
+	}
+
+	updates := make(chan StatusUpdate)
+	runtime.Go("edge.client.stream.read_loop", func() {
+		c.readLoop(ctx, resp.Body, updates)
+	})
+
+	return updates, nil
+}
+
+func (c *SSEClient) readLoop(ctx context.Context, body io.ReadCloser, updates chan<- StatusUpdate) {
+	defer close(updates)
+	defer body.Close()
+
+	idleTimeout := time.AfterFunc(2*pingHeartbeat, func() {
+		log.Error().Msg("status stream idle for too long, closing connection")
+		body.Close()
+	})
+	defer idleTimeout.Stop()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var event string
+	var dataLines []string
+
+	emit := func() {
+		if event == "" {
+			return
+		}
+
+		idleTimeout.Reset(2 * pingHeartbeat)
+
+		if event != "ping" {
+			update := StatusUpdate{Event: event, Data: []byte(strings.Join(dataLines, "\n"))}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+			}
+		}
+
+		event = ""
+		dataLines = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			emit()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error().Err(err).Msg("status stream closed with an error")
+	}
+}
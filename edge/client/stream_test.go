@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLoopParsesEventsAndFiltersPings(t *testing.T) {
+	body := io.NopCloser(strings.NewReader(
+		"event: status\n" +
+			"data: line one\n" +
+			"data: line two\n" +
+			"\n" +
+			"event: ping\n" +
+			"data: ignored\n" +
+			"\n" +
+			"event: tunnel\n" +
+			"data: {\"required\":true}\n" +
+			"\n",
+	))
+
+	updates := make(chan StatusUpdate)
+	c := &SSEClient{}
+
+	go c.readLoop(context.Background(), body, updates)
+
+	var got []StatusUpdate
+	for update := range updates {
+		got = append(got, update)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 non-ping updates, got %d: %+v", len(got), got)
+	}
+
+	if got[0].Event != "status" || string(got[0].Data) != "line one\nline two" {
+		t.Errorf("unexpected first update: %+v", got[0])
+	}
+
+	if got[1].Event != "tunnel" || string(got[1].Data) != `{"required":true}` {
+		t.Errorf("unexpected second update: %+v", got[1])
+	}
+}
+
+func TestReadLoopClosesUpdatesChannelWhenBodyEnds(t *testing.T) {
+	body := io.NopCloser(strings.NewReader("event: status\ndata: {}\n\n"))
+	updates := make(chan StatusUpdate)
+	c := &SSEClient{}
+
+	done := make(chan struct{})
+	go func() {
+		c.readLoop(context.Background(), body, updates)
+		close(done)
+	}()
+
+	<-updates
+
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Fatalf("expected updates channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for updates channel to close")
+	}
+
+	<-done
+}
+
+// TestReadLoopDoesNotBlockForeverOnCancelledContext verifies that emit's
+// send to the (here, never-drained) updates channel doesn't deadlock once
+// ctx is cancelled, even though the goroutine still only fully exits once
+// its body is closed.
+func TestReadLoopDoesNotBlockForeverOnCancelledContext(t *testing.T) {
+	pr, pw := io.Pipe()
+
+	updates := make(chan StatusUpdate) // intentionally never read from
+	c := &SSEClient{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.readLoop(ctx, pr, updates)
+		close(done)
+	}()
+
+	pw.Write([]byte("event: status\ndata: {}\n\n"))
+	pw.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("readLoop blocked instead of returning once its body closed")
+	}
+}
@@ -1,25 +1,41 @@
 package edge
 
 import (
+	"context"
 	"encoding/base64"
-	"github.com/portainer/agent/edge/client"
-	"log"
+	"encoding/json"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/portainer/agent"
 	"github.com/portainer/agent/chisel"
+	"github.com/portainer/agent/edge/client"
 	"github.com/portainer/agent/edge/scheduler"
 	"github.com/portainer/agent/edge/stack"
+	"github.com/portainer/agent/internal/runtime"
+	"github.com/portainer/agent/logger"
+	"github.com/portainer/agent/metrics"
 	"github.com/portainer/libcrypto"
 )
 
 const tunnelActivityCheckInterval = 30 * time.Second
 
+// tunnelByteCounter is implemented by reverse tunnel clients that can
+// report cumulative bytes transferred over the tunnel, e.g. chisel.Client.
+// PollService type-asserts tunnelClient against it so the tunnel byte
+// metrics degrade gracefully (simply staying unset) when the configured
+// client doesn't expose counters.
+type tunnelByteCounter interface {
+	BytesIn() uint64
+	BytesOut() uint64
+}
+
 // PollService is used to poll a Portainer instance to retrieve the status associated to the Edge endpoint.
 // It is responsible for managing the state of the reverse tunnel (open and closing after inactivity).
 // It is also responsible for retrieving the data associated to Edge stacks and schedules.
 type PollService struct {
+	ctx                     context.Context
 	apiServerAddr           string
 	pollIntervalInSeconds   float64
 	inactivityTimeout       time.Duration
@@ -35,6 +51,31 @@ type PollService struct {
 	tunnelServerAddr        string
 	tunnelServerFingerprint string
 	logsManager             *scheduler.LogsManager
+	logger                  *logger.Logger
+	metrics                 *metrics.Metrics
+
+	// lastTunnelBytesIn/Out hold the last cumulative totals read from
+	// tunnelClient via tunnelByteCounter, so the agent_tunnel_bytes_in_total
+	// and agent_tunnel_bytes_out_total counters can be advanced by the delta
+	// observed since the previous activity-monitor tick.
+	lastTunnelBytesIn  uint64
+	lastTunnelBytesOut uint64
+
+	// streamingMode and sseClient back the long-poll/SSE transport; when
+	// streamingMode is true the status-poll goroutine consumes a push
+	// stream instead of short-polling, falling back to short-polling (and
+	// flipping streamingMode back off) on stream errors.
+	streamingMode bool
+	sseClient     *client.SSEClient
+
+	// statusMu guards the backoff/circuit-breaker state below, which is
+	// written from the poll loop goroutine and read from the /status
+	// handler goroutine.
+	statusMu            sync.Mutex
+	consecutiveFailures int
+	lastError           error
+	lastErrorClass      errorClass
+	circuitOpenUntil    time.Time
 }
 
 type pollServiceConfig struct {
@@ -48,6 +89,8 @@ type pollServiceConfig struct {
 	TunnelServerAddr        string
 	TunnelServerFingerprint string
 	EdgeAsyncMode           bool
+	EdgeStreamingMode       bool
+	Metrics                 *metrics.Metrics
 }
 
 // newPollService returns a pointer to a new instance of PollService
@@ -68,7 +111,13 @@ func newPollService(edgeStackManager *stack.StackManager, logsManager *scheduler
 		tunnel = chisel.NewClient()
 	}
 
+	pollLogger := logger.New("edge.poll").
+		With("edge_id", config.EdgeID).
+		With("endpoint_id", config.EndpointID).
+		With("portainer_url", config.PortainerURL)
+
 	pollService := &PollService{
+		ctx:                     context.Background(),
 		apiServerAddr:           config.APIServerAddr,
 		edgeID:                  config.EdgeID,
 		pollIntervalInSeconds:   pollFrequency.Seconds(),
@@ -83,6 +132,13 @@ func newPollService(edgeStackManager *stack.StackManager, logsManager *scheduler
 		tunnelServerFingerprint: config.TunnelServerFingerprint,
 		logsManager:             logsManager,
 		portainerClient:         portainerClient,
+		logger:                  pollLogger,
+		metrics:                 config.Metrics,
+		streamingMode:           config.EdgeStreamingMode,
+	}
+
+	if config.EdgeStreamingMode {
+		pollService.sseClient = client.NewSSEClient(config.PortainerURL, config.EdgeID, nil)
 	}
 
 	return pollService, nil
@@ -99,13 +155,22 @@ func (service *PollService) resetActivityTimer() {
 // if needed as well as manage schedules.
 // The second loop will check for the last activity of the reverse tunnel and close the tunnel if it exceeds the tunnel
 // inactivity duration.
-func (service *PollService) start() error {
+// ctx is the root shutdown context: in addition to stop/Stop being called
+// explicitly, cancelling ctx also unwinds both loops.
+func (service *PollService) start(ctx context.Context) error {
 	if service.refreshSignal != nil {
 		return nil
 	}
 
+	service.ctx = ctx
 	service.refreshSignal = make(chan struct{})
-	service.startStatusPollLoop()
+
+	if service.streamingMode {
+		service.startStreamingLoop()
+	} else {
+		service.startStatusPollLoop()
+	}
+
 	service.startActivityMonitoringLoop()
 
 	return nil
@@ -119,36 +184,214 @@ func (service *PollService) stop() error {
 	return nil
 }
 
-func (service *PollService) restartStatusPollLoop() {
-	service.stop()
-	service.refreshSignal = make(chan struct{})
-	service.startStatusPollLoop()
+// Stop gracefully stops the poll loops. It is the exported counterpart of
+// stop, called by the Supervisor during an orderly shutdown; ctx is
+// currently unused but kept so the shutdown signature is consistent with
+// the other components the Supervisor manages.
+func (service *PollService) Stop(ctx context.Context) error {
+	return service.stop()
 }
 
 func (service *PollService) startStatusPollLoop() error {
-	log.Printf("[DEBUG] [edge] [poll_interval_seconds: %f] [server_url: %s] [message: starting Portainer short-polling client]", service.pollIntervalInSeconds, service.portainerURL)
+	service.logger.Debug().Float64("poll_interval_seconds", service.pollIntervalInSeconds).Msg("starting Portainer short-polling client")
 
-	ticker := time.NewTicker(time.Duration(service.pollIntervalInSeconds) * time.Second)
-	go func() {
+	timer := time.NewTimer(time.Duration(service.pollIntervalInSeconds) * time.Second)
+	runtime.GoSupervised("edge.poll.status", func() {
 		for {
 			select {
-			case <-ticker.C:
-				err := service.poll()
-				if err != nil {
-					log.Printf("[ERROR] [edge] [message: an error occured during short poll] [error: %s]", err)
+			case <-timer.C:
+				if service.circuitOpen() {
+					service.logger.Debug().Msg("circuit breaker open, skipping poll")
+				} else {
+					start := time.Now()
+					err := service.poll()
+					service.recordPollMetrics(err, time.Since(start))
+					service.recordPollResult(err)
+					if err != nil {
+						service.logger.Error().Err(err).Msg("an error occurred during short poll")
+					}
+				}
+
+				nextInterval := service.nextPollInterval()
+				if service.metrics != nil {
+					service.metrics.PollIntervalSeconds.Set(nextInterval.Seconds())
 				}
+				timer.Reset(nextInterval)
 
 			case <-service.refreshSignal:
-				log.Println("[DEBUG] [edge] [message: shutting down Portainer short-polling client]")
-				ticker.Stop()
+				service.logger.Debug().Msg("shutting down Portainer short-polling client")
+				timer.Stop()
+				return
+
+			case <-service.ctx.Done():
+				service.logger.Debug().Msg("shutting down Portainer short-polling client on context cancellation")
+				timer.Stop()
 				return
 			}
 		}
-	}()
+	})
 
 	return nil
 }
 
+// startStreamingLoop replaces the status-poll goroutine with a reader that
+// consumes a push stream of status updates instead of short-polling. It
+// falls back to short-polling, using the existing backoff logic, if the
+// stream can't be opened or is closed unexpectedly.
+func (service *PollService) startStreamingLoop() {
+	service.logger.Debug().Str("server_url", service.portainerURL).Msg("starting Portainer status streaming client")
+
+	runtime.GoSupervised("edge.poll.stream", func() {
+		updates, err := service.sseClient.Stream(service.ctx)
+		if err != nil {
+			service.logger.Error().Err(err).Msg("unable to open status stream, falling back to short-polling")
+			service.recordPollResult(err)
+			service.fallBackToShortPolling()
+			return
+		}
+
+		service.consumeStream(updates)
+
+		select {
+		case <-service.refreshSignal:
+			return
+		case <-service.ctx.Done():
+			return
+		default:
+			service.logger.Error().Msg("status stream closed unexpectedly, falling back to short-polling")
+			service.fallBackToShortPolling()
+		}
+	})
+}
+
+// fallBackToShortPolling switches the poll loop back to short-polling,
+// typically after a status stream error. Subsequent failures are handled by
+// the regular backoff/circuit-breaker logic in startStatusPollLoop.
+func (service *PollService) fallBackToShortPolling() {
+	service.streamingMode = false
+	service.startStatusPollLoop()
+}
+
+func (service *PollService) consumeStream(updates <-chan client.StatusUpdate) {
+	for update := range updates {
+		service.handleStreamUpdate(update)
+	}
+}
+
+// handleStreamUpdate dispatches a single push event to the same handlers
+// the short-poll loop uses (createTunnel, edgeStackManager.UpdateStacksStatus,
+// scheduleManager.Schedule).
+func (service *PollService) handleStreamUpdate(update client.StatusUpdate) {
+	switch update.Event {
+	case "tunnel":
+		service.handleTunnelStreamEvent(update.Data)
+	case "stack":
+		service.handleStackStreamEvent(update.Data)
+	case "schedule":
+		service.handleScheduleStreamEvent(update.Data)
+	case "status":
+		service.handleStatusStreamEvent(update.Data)
+	default:
+		service.logger.Debug().Str("event", update.Event).Msg("received unhandled status stream event")
+	}
+}
+
+func (service *PollService) handleTunnelStreamEvent(data []byte) {
+	if service.tunnelClient == nil {
+		return
+	}
+
+	var payload struct {
+		Required    bool   `json:"required"`
+		Credentials string `json:"credentials"`
+		Port        int    `json:"port"`
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		service.logger.Error().Err(err).Msg("unable to decode tunnel status stream event")
+		return
+	}
+
+	if !payload.Required && service.tunnelClient.IsTunnelOpen() {
+		if err := service.tunnelClient.CloseTunnel(); err != nil {
+			service.logger.Error().Err(err).Msg("unable to shutdown tunnel")
+		} else {
+			service.recordTunnelState(false)
+		}
+		return
+	}
+
+	if payload.Required && !service.tunnelClient.IsTunnelOpen() {
+		if err := service.createTunnel(payload.Credentials, payload.Port); err != nil {
+			service.logger.Error().Err(err).Msg("unable to create tunnel")
+		}
+	}
+}
+
+func (service *PollService) handleStackStreamEvent(data []byte) {
+	var payload struct {
+		Stacks []struct {
+			ID      int `json:"id"`
+			Version int `json:"version"`
+		} `json:"stacks"`
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		service.logger.Error().Err(err).Msg("unable to decode stack status stream event")
+		return
+	}
+
+	stacks := map[int]int{}
+	for _, stack := range payload.Stacks {
+		stacks[stack.ID] = stack.Version
+	}
+
+	err := service.edgeStackManager.UpdateStacksStatus(stacks)
+	if err != nil {
+		service.logger.Error().Err(err).Msg("an error occurred during stack management")
+	}
+	service.recordStackDeployOutcomes(stacks, err)
+}
+
+func (service *PollService) handleScheduleStreamEvent(data []byte) {
+	var payload struct {
+		Schedules []agent.Schedule `json:"schedules"`
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		service.logger.Error().Err(err).Msg("unable to decode schedule status stream event")
+		return
+	}
+
+	scheduleStart := time.Now()
+	if err := service.scheduleManager.Schedule(payload.Schedules); err != nil {
+		service.logger.Error().Err(err).Msg("an error occurred during schedule management")
+	}
+	service.recordScheduleExecution(payload.Schedules, time.Since(scheduleStart))
+
+	logsToCollect := []int{}
+	for _, schedule := range payload.Schedules {
+		if schedule.CollectLogs {
+			logsToCollect = append(logsToCollect, schedule.ID)
+		}
+	}
+
+	service.logsManager.HandleReceivedLogsRequests(logsToCollect)
+}
+
+func (service *PollService) handleStatusStreamEvent(data []byte) {
+	var payload struct {
+		CheckinInterval float64 `json:"checkinInterval"`
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		service.logger.Error().Err(err).Msg("unable to decode status stream event")
+		return
+	}
+
+	service.maybeUpdatePollInterval(payload.CheckinInterval)
+}
+
 func (service *PollService) startActivityMonitoringLoop() {
 	if service.tunnelClient == nil {
 		return
@@ -157,36 +400,147 @@ func (service *PollService) startActivityMonitoringLoop() {
 	ticker := time.NewTicker(tunnelActivityCheckInterval)
 	quit := make(chan struct{})
 
-	log.Printf("[DEBUG] [edge] [monitoring_interval_seconds: %f] [inactivity_timeout: %s] [message: starting activity monitoring loop]", tunnelActivityCheckInterval.Seconds(), service.inactivityTimeout.String())
+	service.logger.Debug().
+		Float64("monitoring_interval_seconds", tunnelActivityCheckInterval.Seconds()).
+		Dur("inactivity_timeout", service.inactivityTimeout).
+		Msg("starting activity monitoring loop")
 
-	go func() {
+	runtime.GoSupervised("edge.poll.activity_monitor", func() {
 		for {
 			select {
 			case <-ticker.C:
 
+				service.recordTunnelByteCounters()
+
 				if service.lastActivity.IsZero() {
 					continue
 				}
 
 				elapsed := time.Since(service.lastActivity)
-				log.Printf("[DEBUG] [edge] [tunnel_last_activity_seconds: %f] [message: tunnel activity monitoring]", elapsed.Seconds())
+				service.logger.Debug().Float64("tunnel_last_activity_seconds", elapsed.Seconds()).Msg("tunnel activity monitoring")
+
+				if service.metrics != nil {
+					service.metrics.TunnelLastActivitySeconds.WithLabelValues(service.endpointID).Set(elapsed.Seconds())
+				}
 
 				if service.tunnelClient != nil && service.tunnelClient.IsTunnelOpen() && elapsed.Seconds() > service.inactivityTimeout.Seconds() {
 
-					log.Printf("[INFO] [edge] [tunnel_last_activity_seconds: %f] [message: shutting down tunnel after inactivity period]", elapsed.Seconds())
+					service.logger.Info().Float64("tunnel_last_activity_seconds", elapsed.Seconds()).Msg("shutting down tunnel after inactivity period")
 
 					err := service.tunnelClient.CloseTunnel()
 					if err != nil {
-						log.Printf("[ERROR] [edge] [message: unable to shutdown tunnel] [error: %s]", err)
+						service.logger.Error().Err(err).Msg("unable to shutdown tunnel")
+					} else {
+						service.recordTunnelState(false)
 					}
 				}
 
 			case <-quit:
 				ticker.Stop()
 				return
+
+			case <-service.ctx.Done():
+				ticker.Stop()
+				return
 			}
 		}
-	}()
+	})
+}
+
+// recordPollMetrics records the outcome and duration of a single poll
+// attempt against the agent_poll_attempts_total and agent_poll_duration_seconds
+// metrics. It is a no-op when no Metrics was configured.
+func (service *PollService) recordPollMetrics(err error, duration time.Duration) {
+	if service.metrics == nil {
+		return
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	service.metrics.PollAttemptsTotal.WithLabelValues(outcome).Inc()
+	service.metrics.PollDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// recordTunnelState updates the tunnel-open gauge for this endpoint. The
+// last-activity gauge is left to the activity-monitoring loop. It is a no-op
+// when no Metrics was configured.
+func (service *PollService) recordTunnelState(open bool) {
+	if service.metrics == nil {
+		return
+	}
+
+	value := 0.0
+	if open {
+		value = 1
+	}
+
+	service.metrics.TunnelOpen.WithLabelValues(service.endpointID).Set(value)
+}
+
+// recordTunnelByteCounters advances the agent_tunnel_bytes_in_total and
+// agent_tunnel_bytes_out_total counters by the delta observed since the
+// last call, reading cumulative totals from tunnelClient via the optional
+// tunnelByteCounter interface. It is a no-op when no Metrics was configured
+// or tunnelClient doesn't implement tunnelByteCounter.
+func (service *PollService) recordTunnelByteCounters() {
+	if service.metrics == nil || service.tunnelClient == nil {
+		return
+	}
+
+	counter, ok := service.tunnelClient.(tunnelByteCounter)
+	if !ok {
+		return
+	}
+
+	bytesIn := counter.BytesIn()
+	bytesOut := counter.BytesOut()
+
+	if bytesIn > service.lastTunnelBytesIn {
+		service.metrics.TunnelBytesIn.WithLabelValues(service.endpointID).Add(float64(bytesIn - service.lastTunnelBytesIn))
+	}
+	service.lastTunnelBytesIn = bytesIn
+
+	if bytesOut > service.lastTunnelBytesOut {
+		service.metrics.TunnelBytesOut.WithLabelValues(service.endpointID).Add(float64(bytesOut - service.lastTunnelBytesOut))
+	}
+	service.lastTunnelBytesOut = bytesOut
+}
+
+// recordScheduleExecution records one agent_schedule_execution_duration_seconds
+// sample per scheduled job, using the duration of the overall Schedule call
+// since the scheduler does not currently report per-job timings. It is a
+// no-op when no Metrics was configured.
+func (service *PollService) recordScheduleExecution(schedules []agent.Schedule, duration time.Duration) {
+	if service.metrics == nil {
+		return
+	}
+
+	for _, schedule := range schedules {
+		service.metrics.ScheduleExecutionDuration.WithLabelValues(strconv.Itoa(schedule.ID)).Observe(duration.Seconds())
+	}
+}
+
+// recordStackDeployOutcomes records one agent_edge_stack_deploys_total
+// sample per stack ID in the batch, using err to classify the whole batch
+// as a success or a failure; UpdateStacksStatus does not currently report
+// which stack within a batch failed, so every stack in the batch is
+// attributed the same outcome. It is a no-op when no Metrics was configured.
+func (service *PollService) recordStackDeployOutcomes(stacks map[int]int, err error) {
+	if service.metrics == nil {
+		return
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	for stackID := range stacks {
+		service.metrics.EdgeStackDeploysTotal.WithLabelValues(strconv.Itoa(stackID), outcome).Inc()
+	}
 }
 
 const clientDefaultPollTimeout = 5
@@ -197,33 +551,42 @@ func (service *PollService) poll() error {
 		return err
 	}
 
-	log.Printf("[DEBUG] [edge] [status: %s] [port: %d] [schedule_count: %d] [checkin_interval_seconds: %f]", responseData.Status, responseData.Port, len(responseData.Schedules), responseData.CheckinInterval)
+	service.logger.Debug().
+		Str("status", responseData.Status).
+		Int("port", responseData.Port).
+		Int("schedule_count", len(responseData.Schedules)).
+		Float64("checkin_interval_seconds", responseData.CheckinInterval).
+		Msg("poll complete")
 
 	if service.tunnelClient != nil {
 		if responseData.Status == "IDLE" && service.tunnelClient.IsTunnelOpen() {
-			log.Printf("[DEBUG] [edge] [status: %s] [message: Idle status detected, shutting down tunnel]", responseData.Status)
+			service.logger.Debug().Str("status", responseData.Status).Msg("idle status detected, shutting down tunnel")
 
 			err := service.tunnelClient.CloseTunnel()
 			if err != nil {
-				log.Printf("[ERROR] [edge] [message: Unable to shutdown tunnel] [error: %s]", err)
+				service.logger.Error().Err(err).Msg("unable to shutdown tunnel")
+			} else {
+				service.recordTunnelState(false)
 			}
 		}
 
 		if responseData.Status == "REQUIRED" && !service.tunnelClient.IsTunnelOpen() {
-			log.Println("[DEBUG] [edge] [message: Required status detected, creating reverse tunnel]")
+			service.logger.Debug().Msg("required status detected, creating reverse tunnel")
 
 			err := service.createTunnel(responseData.Credentials, responseData.Port)
 			if err != nil {
-				log.Printf("[ERROR] [edge] [message: Unable to create tunnel] [error: %s]", err)
+				service.logger.Error().Err(err).Msg("unable to create tunnel")
 				return err
 			}
 		}
 	}
 
+	scheduleStart := time.Now()
 	err = service.scheduleManager.Schedule(responseData.Schedules)
 	if err != nil {
-		log.Printf("[ERROR] [edge] [message: an error occurred during schedule management] [err: %s]", err)
+		service.logger.Error().Err(err).Msg("an error occurred during schedule management")
 	}
+	service.recordScheduleExecution(responseData.Schedules, time.Since(scheduleStart))
 
 	logsToCollect := []int{}
 	for _, schedule := range responseData.Schedules {
@@ -234,12 +597,10 @@ func (service *PollService) poll() error {
 
 	service.logsManager.HandleReceivedLogsRequests(logsToCollect)
 
-	if responseData.CheckinInterval > 0 && responseData.CheckinInterval != service.pollIntervalInSeconds {
-		log.Printf("[DEBUG] [edge] [old_interval: %f] [new_interval: %f] [message: updating poll interval]", service.pollIntervalInSeconds, responseData.CheckinInterval)
-		service.pollIntervalInSeconds = responseData.CheckinInterval
-		service.portainerClient.SetTimeout(time.Duration(responseData.CheckinInterval) * time.Second)
-		go service.restartStatusPollLoop()
-	}
+	// The poll loop re-reads pollIntervalInSeconds as the backoff baseline
+	// on every timer reset, so the new interval takes effect on the next
+	// cycle without restarting the loop.
+	service.maybeUpdatePollInterval(responseData.CheckinInterval)
 
 	if responseData.Stacks != nil {
 		stacks := map[int]int{}
@@ -248,8 +609,9 @@ func (service *PollService) poll() error {
 		}
 
 		err := service.edgeStackManager.UpdateStacksStatus(stacks)
+		service.recordStackDeployOutcomes(stacks, err)
 		if err != nil {
-			log.Printf("[ERROR] [edge] [message: an error occurred during stack management] [error: %s]", err)
+			service.logger.Error().Err(err).Msg("an error occurred during stack management")
 			return err
 		}
 	}
@@ -286,5 +648,6 @@ func (service *PollService) createTunnel(encodedCredentials string, remotePort i
 	}
 
 	service.resetActivityTimer()
+	service.recordTunnelState(true)
 	return nil
 }
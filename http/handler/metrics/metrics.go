@@ -0,0 +1,31 @@
+// Package metrics exposes the /metrics endpoint in Prometheus text
+// exposition format. Unlike the other handler packages it is not wired
+// through libhttp's JSON response helpers, since Prometheus expects a plain
+// text/plain body; the router registering this handler is expected to gate
+// it behind the same edge-key validation middleware used for the other
+// Edge endpoints before exposing it.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/portainer/agent/metrics"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler is the HTTP handler for the /metrics endpoint.
+type Handler struct {
+	Registry *metrics.Registry
+}
+
+// NewHandler returns a pointer to a metrics Handler.
+func NewHandler(registry *metrics.Registry) *Handler {
+	return &Handler{Registry: registry}
+}
+
+// ServeHTTP renders the registry in Prometheus text exposition format.
+func (handler *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", prometheusContentType)
+	handler.Registry.Expose(rw)
+}
@@ -0,0 +1,35 @@
+// Package status exposes the /status endpoint, which reports the Edge poll
+// loop's current backoff/circuit-breaker state so operators can tell why an
+// agent stopped checking in without having to scrape its logs.
+package status
+
+import (
+	"net/http"
+
+	"github.com/portainer/agent/edge"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// PollStatusProvider is implemented by the edge.Manager to expose the
+// underlying PollService's backoff/circuit-breaker state.
+type PollStatusProvider interface {
+	PollStatus() edge.PollStatus
+}
+
+// Handler is the HTTP handler for the /status endpoint.
+type Handler struct {
+	PollStatusProvider PollStatusProvider
+}
+
+// NewHandler returns a pointer to a status Handler.
+func NewHandler(pollStatusProvider PollStatusProvider) *Handler {
+	return &Handler{PollStatusProvider: pollStatusProvider}
+}
+
+// GET request on /status
+func (handler *Handler) statusInspect(rw http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	status := handler.PollStatusProvider.PollStatus()
+
+	return response.JSON(rw, status)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"log"
 	"net/http"
 	"time"
 
@@ -12,7 +11,10 @@ import (
 	"github.com/portainer/agent/edge"
 	"github.com/portainer/agent/exec"
 	"github.com/portainer/agent/http/handler"
+	"github.com/portainer/agent/internal/runtime"
 	"github.com/portainer/agent/kubernetes"
+	"github.com/portainer/agent/logger"
+	"github.com/portainer/agent/metrics"
 	httperror "github.com/portainer/libhttp/error"
 )
 
@@ -29,8 +31,17 @@ type APIServer struct {
 	kubeClient         *kubernetes.KubeClient
 	kubernetesDeployer *exec.KubernetesDeployer
 	containerPlatform  agent.ContainerPlatform
+	logger             *logger.Logger
+	httpServer         *http.Server
+	metrics            *metrics.Metrics
+	shutdownTimeout    time.Duration
 }
 
+// defaultShutdownTimeout bounds how long the server waits for in-flight
+// requests to complete when the root context is cancelled and no
+// ShutdownTimeout was configured.
+const defaultShutdownTimeout = 5 * time.Second
+
 // APIServerConfig represents a server configuration
 // used to create a new API server
 type APIServerConfig struct {
@@ -45,10 +56,22 @@ type APIServerConfig struct {
 	RuntimeConfiguration *agent.RuntimeConfiguration
 	AgentOptions         *agent.Options
 	ContainerPlatform    agent.ContainerPlatform
+	Metrics              *metrics.Metrics
+	// ShutdownTimeout bounds how long StartUnsecured/StartSecured wait for
+	// in-flight requests to complete when the root context passed to them
+	// is cancelled. It is wired through the same --shutdown-timeout flag
+	// used to configure the Supervisor, so both give up after the same
+	// grace period. Defaults to defaultShutdownTimeout when zero.
+	ShutdownTimeout time.Duration
 }
 
 // NewAPIServer returns a pointer to a APIServer.
 func NewAPIServer(config *APIServerConfig) *APIServer {
+	shutdownTimeout := config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
 	return &APIServer{
 		addr:               config.Addr,
 		port:               config.Port,
@@ -61,6 +84,9 @@ func NewAPIServer(config *APIServerConfig) *APIServer {
 		kubeClient:         config.KubeClient,
 		kubernetesDeployer: config.KubernetesDeployer,
 		containerPlatform:  config.ContainerPlatform,
+		logger:             logger.New("http.server"),
+		metrics:            config.Metrics,
+		shutdownTimeout:    shutdownTimeout,
 	}
 }
 
@@ -78,7 +104,9 @@ func (server *APIServer) enhanceAPIForEdgeMode(next http.Handler, isSecure bool)
 }
 
 // Start starts a new web server by listening on the specified listenAddr.
-func (server *APIServer) StartUnsecured(edgeMode bool) error {
+// ctx is the Supervisor's root context: when it is cancelled the server is
+// shut down instead of relying solely on an explicit call to Shutdown.
+func (server *APIServer) StartUnsecured(ctx context.Context, edgeMode bool) error {
 	config := &handler.Config{
 		SystemService:        server.systemService,
 		ClusterService:       server.clusterService,
@@ -89,6 +117,7 @@ func (server *APIServer) StartUnsecured(edgeMode bool) error {
 		KubernetesDeployer:   server.kubernetesDeployer,
 		Secured:              false,
 		ContainerPlatform:    server.containerPlatform,
+		Metrics:              server.metrics,
 	}
 
 	var h http.Handler = handler.NewHandler(config)
@@ -98,7 +127,12 @@ func (server *APIServer) StartUnsecured(edgeMode bool) error {
 		h = server.enhanceAPIForEdgeMode(h, false)
 	}
 
-	log.Printf("[INFO] [http] [server_addr: %s] [server_port: %s] [secured: %t] [api_version: %s] [message: Starting Agent API server]", server.addr, server.port, config.Secured, agent.Version)
+	server.logger.Info().
+		Str("server_addr", server.addr).
+		Str("server_port", server.port).
+		Bool("secured", config.Secured).
+		Str("api_version", agent.Version).
+		Msg("starting Agent API server")
 
 	httpServer := &http.Server{
 		Addr:         listenAddr,
@@ -106,12 +140,24 @@ func (server *APIServer) StartUnsecured(edgeMode bool) error {
 		ReadTimeout:  120 * time.Second,
 		WriteTimeout: 30 * time.Minute,
 	}
+	server.httpServer = httpServer
+
+	runtime.Go("http.server.shutdown_on_cancel", func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	})
 
 	return httpServer.ListenAndServe()
 }
 
 // Start starts a new web server by listening on the specified listenAddr.
-func (server *APIServer) StartSecured(edgeMode bool) error {
+// ctx is the Supervisor's root context: when it is cancelled the server is
+// shut down instead of relying solely on an explicit call to Shutdown.
+func (server *APIServer) StartSecured(ctx context.Context, edgeMode bool) error {
 	config := &handler.Config{
 		SystemService:        server.systemService,
 		ClusterService:       server.clusterService,
@@ -122,6 +168,7 @@ func (server *APIServer) StartSecured(edgeMode bool) error {
 		KubernetesDeployer:   server.kubernetesDeployer,
 		Secured:              true,
 		ContainerPlatform:    server.containerPlatform,
+		Metrics:              server.metrics,
 	}
 
 	var h http.Handler = handler.NewHandler(config)
@@ -131,7 +178,12 @@ func (server *APIServer) StartSecured(edgeMode bool) error {
 		h = server.enhanceAPIForEdgeMode(h, true)
 	}
 
-	log.Printf("[INFO] [http] [server_addr: %s] [server_port: %s] [secured: %t] [api_version: %s] [message: Starting Agent API server]", server.addr, server.port, config.Secured, agent.Version)
+	server.logger.Info().
+		Str("server_addr", server.addr).
+		Str("server_port", server.port).
+		Bool("secured", config.Secured).
+		Str("api_version", agent.Version).
+		Msg("starting Agent API server")
 
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS13,
@@ -144,21 +196,44 @@ func (server *APIServer) StartSecured(edgeMode bool) error {
 		TLSConfig:    tlsConfig,
 		WriteTimeout: 30 * time.Minute,
 	}
+	server.httpServer = httpServer
+
+	runtime.Go("http.server.shutdown_on_cancel", func() {
+		<-ctx.Done()
 
-	go func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	})
+
+	runtime.Go("http.server.security_shutdown", func() {
 		securityShutdown := server.agentOptions.AgentSecurityShutdown
 		time.Sleep(securityShutdown)
 
 		if !server.signatureService.IsAssociated() {
-			log.Printf("[INFO] [main,http] [message: Shutting down API server as no client was associated after %s, keeping alive to prevent restart by docker/kubernetes]", securityShutdown)
+			server.logger.Info().Dur("security_shutdown", securityShutdown).Msg("shutting down API server as no client was associated, keeping alive to prevent restart by docker/kubernetes")
 
 			err := httpServer.Shutdown(context.Background())
 			if err != nil {
-				log.Fatalf("[ERROR] [server] [message: failed shutting down server] [error: %s]", err)
+				server.logger.Fatal().Err(err).Msg("failed shutting down server")
 			}
 
 		}
-	}()
+	})
 
 	return httpServer.ListenAndServeTLS(agent.TLSCertPath, agent.TLSKeyPath)
 }
+
+// Shutdown gracefully stops the underlying http.Server, waiting for
+// in-flight requests to complete or ctx to expire, whichever comes first.
+// It is safe to call even if the server has not started yet or has already
+// stopped. This is the shutdown function the Supervisor invokes for the API
+// server component.
+func (server *APIServer) Shutdown(ctx context.Context) error {
+	if server.httpServer == nil {
+		return nil
+	}
+
+	return server.httpServer.Shutdown(ctx)
+}
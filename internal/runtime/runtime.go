@@ -0,0 +1,168 @@
+// Package runtime provides crash-recovery helpers for the long-lived
+// goroutines the agent spawns (the Edge poll loops, the activity monitor,
+// the API server shutdown watcher, ...). A panic inside one of those
+// goroutines used to take down the whole process silently; Go and
+// GoSupervised recover it, log it, and - for supervised goroutines that are
+// meant to run forever - restart the work after a backoff.
+package runtime
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/portainer/agent/logger"
+)
+
+var log = logger.New("runtime")
+
+// CrashHandler is invoked whenever Go or GoSupervised recovers a panic. It
+// receives the recovered value and the stack trace captured at the point of
+// the panic. Tests can install their own handler via SetCrashHandler to
+// assert that a panic was observed without relying on log output.
+type CrashHandler func(label string, recovered interface{}, stack []byte)
+
+var (
+	handlerMu    sync.RWMutex
+	crashHandler CrashHandler = defaultCrashHandler
+	panicCount   int64
+)
+
+// SetCrashHandler installs h as the process-wide crash handler, replacing
+// the default one that logs the panic. Passing nil restores the default
+// handler. This is primarily meant for tests.
+func SetCrashHandler(h CrashHandler) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+	if h == nil {
+		h = defaultCrashHandler
+	}
+	crashHandler = h
+}
+
+func defaultCrashHandler(label string, recovered interface{}, stack []byte) {
+	log.Error().
+		Str("goroutine", label).
+		Str("panic", errString(recovered)).
+		Str("stack", string(stack)).
+		Msg("recovered from panic")
+}
+
+func errString(recovered interface{}) string {
+	if err, ok := recovered.(error); ok {
+		return err.Error()
+	}
+	if s, ok := recovered.(string); ok {
+		return s
+	}
+	return fmt.Sprint(recovered)
+}
+
+// PanicCount returns the number of panics recovered by Go or GoSupervised
+// since process start.
+func PanicCount() int64 {
+	return atomic.LoadInt64(&panicCount)
+}
+
+func handleCrash(label string, recovered interface{}) {
+	atomic.AddInt64(&panicCount, 1)
+
+	handlerMu.RLock()
+	handler := crashHandler
+	handlerMu.RUnlock()
+
+	handler(label, recovered, debug.Stack())
+}
+
+// Go runs fn in a new goroutine. If fn panics, the panic is recovered and
+// reported to the crash handler instead of crashing the process; fn is not
+// restarted. Use this for fire-and-forget goroutines such as a shutdown
+// watcher.
+func Go(label string, fn func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				handleCrash(label, r)
+			}
+		}()
+		fn()
+	}()
+}
+
+// Default restart backoff bounds for GoSupervised.
+const (
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 30 * time.Second
+
+	// restartWindow and maxRestartsInWindow together cap the restart rate:
+	// if fn crashes more than maxRestartsInWindow times within
+	// restartWindow, GoSupervised gives up instead of looping forever.
+	restartWindow       = 1 * time.Minute
+	maxRestartsInWindow = 10
+)
+
+// GoSupervised runs fn in a new goroutine and keeps it alive: if fn panics,
+// the panic is recovered, reported to the crash handler, and fn is
+// re-invoked after a short backoff so the caller's loop self-heals. If fn
+// returns normally (no panic), GoSupervised stops - it is meant for
+// functions such as poll loops that only return via an explicit shutdown
+// signal. The restart rate is capped: if fn crashes too many times in a
+// short window, GoSupervised logs a final error and stops restarting it to
+// avoid a tight crash loop.
+func GoSupervised(label string, fn func()) {
+	go func() {
+		backoff := defaultMinBackoff
+		var restarts []time.Time
+
+		for {
+			crashed := runRecovered(label, fn)
+			if !crashed {
+				return
+			}
+
+			now := time.Now()
+			restarts = append(restarts, now)
+			restarts = dropOlderThan(restarts, now.Add(-restartWindow))
+
+			if len(restarts) > maxRestartsInWindow {
+				log.Error().
+					Str("goroutine", label).
+					Int("restarts", len(restarts)).
+					Dur("window", restartWindow).
+					Msg("too many panics in a short window, giving up on restarting goroutine")
+				return
+			}
+
+			log.Info().Str("goroutine", label).Dur("backoff", backoff).Msg("restarting goroutine after panic")
+			time.Sleep(backoff)
+
+			backoff *= 2
+			if backoff > defaultMaxBackoff {
+				backoff = defaultMaxBackoff
+			}
+		}
+	}()
+}
+
+func dropOlderThan(restarts []time.Time, cutoff time.Time) []time.Time {
+	kept := restarts[:0]
+	for _, t := range restarts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func runRecovered(label string, fn func()) (crashed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			crashed = true
+			handleCrash(label, r)
+		}
+	}()
+	fn()
+	return false
+}
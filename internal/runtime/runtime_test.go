@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGoRecoversPanicAndReportsIt(t *testing.T) {
+	var observed int32
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	SetCrashHandler(func(label string, recovered interface{}, stack []byte) {
+		atomic.StoreInt32(&observed, 1)
+		wg.Done()
+	})
+	defer SetCrashHandler(nil)
+
+	Go("test.go", func() {
+		panic("boom")
+	})
+
+	wg.Wait()
+
+	if atomic.LoadInt32(&observed) != 1 {
+		t.Fatalf("expected the crash handler to observe the panic")
+	}
+}
+
+func TestGoSupervisedRestartsAfterPanic(t *testing.T) {
+	var calls int32
+	done := make(chan struct{})
+
+	SetCrashHandler(func(label string, recovered interface{}, stack []byte) {})
+	defer SetCrashHandler(nil)
+
+	GoSupervised("test.supervised", func() {
+		n := atomic.AddInt32(&calls, 1)
+		if n < 3 {
+			panic("boom")
+		}
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the supervised goroutine to be restarted after panicking")
+	}
+
+	if atomic.LoadInt32(&calls) < 3 {
+		t.Fatalf("expected at least 3 invocations, got %d", calls)
+	}
+}
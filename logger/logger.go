@@ -0,0 +1,281 @@
+// Package logger provides a small structured, leveled logger used across the
+// long-lived subsystems of the agent (the Edge poll service, the API server,
+// the Docker wrappers, ...). Each subsystem creates its own *Logger via New,
+// attaches the fields that identify it (component, edge_id, endpoint_id,
+// portainer_url, ...) and every subsequent log line automatically carries
+// them, so operators no longer have to reconstruct context by hand from
+// scattered log.Printf calls.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level represents the severity of a log line.
+type Level int
+
+// Supported log levels, ordered from most to least verbose.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	case FatalLevel:
+		return "FATAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseLevel converts a --log-level flag value into a Level, defaulting to
+// InfoLevel for unrecognized input.
+func ParseLevel(value string) Level {
+	switch strings.ToLower(value) {
+	case "debug":
+		return DebugLevel
+	case "warn", "warning":
+		return WarnLevel
+	case "error":
+		return ErrorLevel
+	case "fatal":
+		return FatalLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// Format controls how a log line is rendered.
+type Format int
+
+// Supported output formats.
+const (
+	// ConsoleFormat renders a human friendly "[key: value] ... message" line,
+	// suitable for local development.
+	ConsoleFormat Format = iota
+	// JSONFormat renders one JSON object per line, suitable for shipping to
+	// a log aggregator.
+	JSONFormat
+)
+
+// ParseFormat converts a --log-format flag value into a Format, defaulting to
+// ConsoleFormat for unrecognized input.
+func ParseFormat(value string) Format {
+	if strings.ToLower(value) == "json" {
+		return JSONFormat
+	}
+	return ConsoleFormat
+}
+
+var (
+	mu           sync.RWMutex
+	globalLevel            = InfoLevel
+	globalFormat           = ConsoleFormat
+	globalOutput io.Writer = os.Stdout
+)
+
+// Configure sets the process-wide level and format. They are read each time
+// a log event starts, so they apply to every Logger, including ones created
+// via New before Configure was called. It is meant to be called once at
+// startup from the values of the --log-level and --log-format flags.
+func Configure(level Level, format Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	globalLevel = level
+	globalFormat = format
+}
+
+// field is a single sticky key/value pair attached to a Logger.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Logger is a leveled logger that carries a fixed set of sticky fields
+// (e.g. component, edge_id, endpoint_id) which are included on every line it
+// writes.
+type Logger struct {
+	fields []field
+}
+
+// New returns a Logger whose lines are tagged with component=name. Callers
+// typically attach further sticky fields with With, e.g.
+//
+//	logger.New("edge.poll").With("edge_id", edgeID).With("portainer_url", url)
+func New(component string) *Logger {
+	return &Logger{fields: []field{{key: "component", value: component}}}
+}
+
+// With returns a copy of the Logger with an additional sticky field. The
+// original Logger is left untouched so it can be reused as a base for
+// multiple derived loggers.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	fields = append(fields, field{key: key, value: value})
+	return &Logger{fields: fields}
+}
+
+// Debug starts a Debug-level log event.
+func (l *Logger) Debug() *Event { return l.newEvent(DebugLevel) }
+
+// Info starts an Info-level log event.
+func (l *Logger) Info() *Event { return l.newEvent(InfoLevel) }
+
+// Warn starts a Warn-level log event.
+func (l *Logger) Warn() *Event { return l.newEvent(WarnLevel) }
+
+// Error starts an Error-level log event.
+func (l *Logger) Error() *Event { return l.newEvent(ErrorLevel) }
+
+// Fatal starts a Fatal-level log event. Msg terminates the process after
+// writing the line.
+func (l *Logger) Fatal() *Event { return l.newEvent(FatalLevel) }
+
+func (l *Logger) newEvent(level Level) *Event {
+	mu.RLock()
+	enabled := level >= globalLevel
+	format := globalFormat
+	out := globalOutput
+	mu.RUnlock()
+
+	return &Event{
+		logger:  l,
+		level:   level,
+		enabled: enabled,
+		format:  format,
+		out:     out,
+	}
+}
+
+// Event accumulates fields for a single log line; it is terminated by Msg.
+type Event struct {
+	logger  *Logger
+	level   Level
+	enabled bool
+	format  Format
+	out     io.Writer
+	extra   []field
+}
+
+// Str attaches a string field to the event.
+func (e *Event) Str(key, value string) *Event { return e.add(key, value) }
+
+// Int attaches an int field to the event.
+func (e *Event) Int(key string, value int) *Event { return e.add(key, value) }
+
+// Float64 attaches a float64 field to the event.
+func (e *Event) Float64(key string, value float64) *Event { return e.add(key, value) }
+
+// Err attaches the standard "error" field to the event.
+func (e *Event) Err(err error) *Event {
+	if err == nil {
+		return e
+	}
+	return e.add("error", err.Error())
+}
+
+// Dur attaches a duration field, rendered as a Go duration string.
+func (e *Event) Dur(key string, value time.Duration) *Event {
+	return e.add(key, value.String())
+}
+
+// Bool attaches a bool field to the event.
+func (e *Event) Bool(key string, value bool) *Event { return e.add(key, value) }
+
+func (e *Event) add(key string, value interface{}) *Event {
+	if !e.enabled {
+		return e
+	}
+	e.extra = append(e.extra, field{key: key, value: value})
+	return e
+}
+
+// Msg writes the event with the given message and terminates it. Calling
+// Msg on a disabled event (level below the configured global level) is a
+// no-op other than a Fatal event, which still exits the process.
+func (e *Event) Msg(msg string) {
+	if e.enabled {
+		line := e.render(msg)
+		fmt.Fprintln(e.out, line)
+	}
+
+	if e.level == FatalLevel {
+		os.Exit(1)
+	}
+}
+
+func (e *Event) render(msg string) string {
+	all := make([]field, 0, len(e.logger.fields)+len(e.extra)+2)
+	all = append(all, field{key: "time", value: time.Now().Format(time.RFC3339)})
+	all = append(all, field{key: "level", value: e.level.String()})
+	all = append(all, e.logger.fields...)
+	all = append(all, e.extra...)
+
+	if e.format == JSONFormat {
+		return renderJSON(all, msg)
+	}
+	return renderConsole(all, msg)
+}
+
+func renderConsole(fields []field, msg string) string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "[%s: %v] ", f.key, f.value)
+	}
+	b.WriteString(msg)
+	return b.String()
+}
+
+func renderJSON(fields []field, msg string) string {
+	// Keep output deterministic by writing fields in insertion order, then
+	// the message last. Values are marshalled with encoding/json rather
+	// than fmt's %q (Go string-escaping), since %q can emit \xHH escapes
+	// for invalid UTF-8/control bytes that encoding/json.Unmarshal rejects.
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(jsonString(f.key))
+		b.WriteByte(':')
+		b.WriteString(jsonString(fmt.Sprintf("%v", f.value)))
+	}
+	if len(fields) > 0 {
+		b.WriteByte(',')
+	}
+	b.WriteString(jsonString("message"))
+	b.WriteByte(':')
+	b.WriteString(jsonString(msg))
+	b.WriteByte('}')
+	return b.String()
+}
+
+// jsonString renders s as a JSON string literal, escaping per RFC 8259
+// rather than Go's %q/strconv.Quote rules.
+func jsonString(s string) string {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return `""`
+	}
+	return string(encoded)
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLoggerIncludesStickyFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	mu.Lock()
+	globalOutput = &buf
+	globalLevel = DebugLevel
+	globalFormat = ConsoleFormat
+	mu.Unlock()
+
+	log := New("edge.poll").With("edge_id", "abc123").With("endpoint_id", 42)
+	log.Debug().Str("status", "IDLE").Msg("poll complete")
+
+	out := buf.String()
+	for _, want := range []string{"component: edge.poll", "edge_id: abc123", "endpoint_id: 42", "status: IDLE", "poll complete"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log output to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestEventBelowGlobalLevelIsSuppressed(t *testing.T) {
+	var buf bytes.Buffer
+
+	mu.Lock()
+	globalOutput = &buf
+	globalLevel = WarnLevel
+	globalFormat = ConsoleFormat
+	mu.Unlock()
+
+	New("edge.poll").Debug().Msg("should not appear")
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no output below the configured level, got %q", buf.String())
+	}
+}
+
+func TestJSONFormatProducesValidJSONForControlAndInvalidUTF8Bytes(t *testing.T) {
+	var buf bytes.Buffer
+
+	mu.Lock()
+	globalOutput = &buf
+	globalLevel = DebugLevel
+	globalFormat = JSONFormat
+	mu.Unlock()
+
+	badString := "line one\nline two\x1f" + string([]byte{0xff, 0xfe}) + "end"
+
+	New("edge.poll").Debug().Str("detail", badString).Msg("bad bytes")
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("expected valid JSON output, got %q: %v", buf.String(), err)
+	}
+
+	if decoded["detail"] == "" {
+		t.Errorf("expected detail field to be present, got %v", decoded)
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	if ParseLevel("debug") != DebugLevel {
+		t.Errorf("expected debug to parse to DebugLevel")
+	}
+	if ParseLevel("bogus") != InfoLevel {
+		t.Errorf("expected unrecognized level to default to InfoLevel")
+	}
+	if ParseFormat("json") != JSONFormat {
+		t.Errorf("expected json to parse to JSONFormat")
+	}
+	if ParseFormat("bogus") != ConsoleFormat {
+		t.Errorf("expected unrecognized format to default to ConsoleFormat")
+	}
+}
@@ -0,0 +1,372 @@
+// Package metrics implements the small set of Prometheus metric types
+// (counter, gauge, histogram, and their labeled "vec" variants) and a
+// Registry that renders them in the Prometheus text exposition format. It
+// exists instead of a dependency on prometheus/client_golang because this
+// module has no go.mod/vendored dependencies to pull one in; swapping this
+// package for the real client library is a drop-in change once it does.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry collects every metric exposed on /metrics.
+type Registry struct {
+	mu     sync.Mutex
+	order  []string
+	byName map[string]metric
+}
+
+type metric interface {
+	name() string
+	help() string
+	metricType() string
+	writeSamples(w io.Writer)
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]metric)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byName[m.name()]; exists {
+		panic("metrics: duplicate metric name " + m.name())
+	}
+
+	r.byName[m.name()] = m
+	r.order = append(r.order, m.name())
+}
+
+// Expose renders every registered metric in Prometheus text exposition
+// format, in registration order.
+func (r *Registry) Expose(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.order {
+		m := r.byName[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name(), m.help())
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name(), m.metricType())
+		m.writeSamples(w)
+	}
+}
+
+// labelString renders a label set as Prometheus's `{k="v",...}` suffix, or
+// an empty string when there are no labels.
+func labelString(labelNames, labelValues []string) string {
+	if len(labelNames) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(labelNames))
+	for i, name := range labelNames {
+		parts[i] = fmt.Sprintf("%s=%q", name, labelValues[i])
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// Counter is a monotonically increasing value, e.g. a count of attempts.
+type Counter struct {
+	metricName string
+	metricHelp string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewCounter creates and registers a Counter.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{metricName: name, metricHelp: help}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increments the counter by delta, which must be non-negative.
+func (c *Counter) Add(delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value += delta
+}
+
+func (c *Counter) name() string       { return c.metricName }
+func (c *Counter) help() string       { return c.metricHelp }
+func (c *Counter) metricType() string { return "counter" }
+func (c *Counter) writeSamples(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "%s %v\n", c.metricName, c.value)
+}
+
+// Gauge is a value that can go up or down, e.g. the current poll interval.
+type Gauge struct {
+	metricName string
+	metricHelp string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewGauge creates and registers a Gauge.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{metricName: name, metricHelp: help}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge to value.
+func (g *Gauge) Set(value float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = value
+}
+
+func (g *Gauge) name() string       { return g.metricName }
+func (g *Gauge) help() string       { return g.metricHelp }
+func (g *Gauge) metricType() string { return "gauge" }
+func (g *Gauge) writeSamples(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "%s %v\n", g.metricName, g.value)
+}
+
+// vecEntry holds the accumulated samples for one label combination.
+type vecEntry struct {
+	labelValues []string
+	counter     float64
+	// histogram-only
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+// CounterVec is a Counter with one value per distinct label combination,
+// e.g. poll attempts partitioned by outcome.
+type CounterVec struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+	mu         sync.Mutex
+	entries    map[string]*vecEntry
+	order      []string
+}
+
+// NewCounterVec creates and registers a CounterVec.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{metricName: name, metricHelp: help, labelNames: labelNames, entries: make(map[string]*vecEntry)}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues returns a handle to increment the counter for the given
+// label combination, in the same order as the labelNames passed to
+// NewCounterVec.
+func (v *CounterVec) WithLabelValues(labelValues ...string) *vecHandle {
+	return &vecHandle{vec: v, labelValues: labelValues}
+}
+
+type vecHandle struct {
+	vec         *CounterVec
+	labelValues []string
+}
+
+// Inc increments the counter for this label combination by 1.
+func (h *vecHandle) Inc() { h.Add(1) }
+
+// Add increments the counter for this label combination by delta.
+func (h *vecHandle) Add(delta float64) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+
+	key := labelKey(h.labelValues)
+	entry, ok := h.vec.entries[key]
+	if !ok {
+		entry = &vecEntry{labelValues: h.labelValues}
+		h.vec.entries[key] = entry
+		h.vec.order = append(h.vec.order, key)
+	}
+	entry.counter += delta
+}
+
+func (v *CounterVec) name() string       { return v.metricName }
+func (v *CounterVec) help() string       { return v.metricHelp }
+func (v *CounterVec) metricType() string { return "counter" }
+func (v *CounterVec) writeSamples(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := append([]string(nil), v.order...)
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := v.entries[key]
+		fmt.Fprintf(w, "%s%s %v\n", v.metricName, labelString(v.labelNames, entry.labelValues), entry.counter)
+	}
+}
+
+// GaugeVec is a Gauge with one value per distinct label combination, e.g.
+// tunnel-open state partitioned by endpoint ID.
+type GaugeVec struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+	mu         sync.Mutex
+	entries    map[string]*vecEntry
+	order      []string
+}
+
+// NewGaugeVec creates and registers a GaugeVec.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	v := &GaugeVec{metricName: name, metricHelp: help, labelNames: labelNames, entries: make(map[string]*vecEntry)}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues returns a handle to set the gauge for the given label
+// combination, in the same order as the labelNames passed to NewGaugeVec.
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *gaugeVecHandle {
+	return &gaugeVecHandle{vec: v, labelValues: labelValues}
+}
+
+type gaugeVecHandle struct {
+	vec         *GaugeVec
+	labelValues []string
+}
+
+// Set sets the gauge for this label combination to value.
+func (h *gaugeVecHandle) Set(value float64) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+
+	key := labelKey(h.labelValues)
+	entry, ok := h.vec.entries[key]
+	if !ok {
+		entry = &vecEntry{labelValues: h.labelValues}
+		h.vec.entries[key] = entry
+		h.vec.order = append(h.vec.order, key)
+	}
+	entry.counter = value
+}
+
+func (v *GaugeVec) name() string       { return v.metricName }
+func (v *GaugeVec) help() string       { return v.metricHelp }
+func (v *GaugeVec) metricType() string { return "gauge" }
+func (v *GaugeVec) writeSamples(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := append([]string(nil), v.order...)
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := v.entries[key]
+		fmt.Fprintf(w, "%s%s %v\n", v.metricName, labelString(v.labelNames, entry.labelValues), entry.counter)
+	}
+}
+
+// DefaultBuckets are the histogram bucket boundaries (in seconds) used when
+// none are supplied, tuned for sub-second to multi-minute agent operations
+// (HTTP polls, Docker calls, schedule runs).
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// HistogramVec observes a distribution of values (typically durations in
+// seconds) per distinct label combination, e.g. poll latency by outcome.
+type HistogramVec struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+	buckets    []float64
+	mu         sync.Mutex
+	entries    map[string]*vecEntry
+	order      []string
+}
+
+// NewHistogramVec creates and registers a HistogramVec with DefaultBuckets.
+func (r *Registry) NewHistogramVec(name, help string, labelNames ...string) *HistogramVec {
+	return r.NewHistogramVecWithBuckets(name, help, DefaultBuckets, labelNames...)
+}
+
+// NewHistogramVecWithBuckets creates and registers a HistogramVec with
+// explicit bucket boundaries.
+func (r *Registry) NewHistogramVecWithBuckets(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	v := &HistogramVec{
+		metricName: name,
+		metricHelp: help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		entries:    make(map[string]*vecEntry),
+	}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues returns a handle to observe a value for the given label
+// combination, in the same order as the labelNames passed to
+// NewHistogramVec.
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *histogramVecHandle {
+	return &histogramVecHandle{vec: v, labelValues: labelValues}
+}
+
+type histogramVecHandle struct {
+	vec         *HistogramVec
+	labelValues []string
+}
+
+// Observe records value (typically a duration in seconds) for this label
+// combination.
+func (h *histogramVecHandle) Observe(value float64) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+
+	key := labelKey(h.labelValues)
+	entry, ok := h.vec.entries[key]
+	if !ok {
+		entry = &vecEntry{labelValues: h.labelValues, bucketCounts: make([]uint64, len(h.vec.buckets))}
+		h.vec.entries[key] = entry
+		h.vec.order = append(h.vec.order, key)
+	}
+
+	entry.sum += value
+	entry.count++
+	for i, bound := range h.vec.buckets {
+		if value <= bound {
+			entry.bucketCounts[i]++
+		}
+	}
+}
+
+func (v *HistogramVec) name() string       { return v.metricName }
+func (v *HistogramVec) help() string       { return v.metricHelp }
+func (v *HistogramVec) metricType() string { return "histogram" }
+func (v *HistogramVec) writeSamples(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := append([]string(nil), v.order...)
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := v.entries[key]
+		for i, bound := range v.buckets {
+			labels := append(append([]string{}, v.labelNames...), "le")
+			values := append(append([]string{}, entry.labelValues...), fmt.Sprintf("%v", bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, labelString(labels, values), entry.bucketCounts[i])
+		}
+		labels := append([]string{}, v.labelNames...)
+		labels = append(labels, "le")
+		values := append(append([]string{}, entry.labelValues...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, labelString(labels, values), entry.count)
+		fmt.Fprintf(w, "%s_sum%s %v\n", v.metricName, labelString(v.labelNames, entry.labelValues), entry.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metricName, labelString(v.labelNames, entry.labelValues), entry.count)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	registry := NewRegistry()
+
+	counter := registry.NewCounter("requests_total", "Total requests.")
+	counter.Inc()
+	counter.Add(2)
+
+	gauge := registry.NewGauge("temperature", "Current temperature.")
+	gauge.Set(42.5)
+
+	var buf bytes.Buffer
+	registry.Expose(&buf)
+	output := buf.String()
+
+	if !strings.Contains(output, "requests_total 3\n") {
+		t.Errorf("expected counter sample in output, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, "temperature 42.5\n") {
+		t.Errorf("expected gauge sample in output, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, "# HELP requests_total Total requests.\n") {
+		t.Errorf("expected HELP line in output, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, "# TYPE requests_total counter\n") {
+		t.Errorf("expected TYPE line in output, got:\n%s", output)
+	}
+}
+
+func TestCounterVecLabelsAndOrdering(t *testing.T) {
+	registry := NewRegistry()
+	vec := registry.NewCounterVec("poll_attempts_total", "Poll attempts.", "outcome")
+
+	vec.WithLabelValues("success").Add(2)
+	vec.WithLabelValues("failure").Inc()
+
+	var buf bytes.Buffer
+	registry.Expose(&buf)
+	output := buf.String()
+
+	if !strings.Contains(output, `poll_attempts_total{outcome="failure"} 1`) {
+		t.Errorf("expected failure sample, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, `poll_attempts_total{outcome="success"} 2`) {
+		t.Errorf("expected success sample, got:\n%s", output)
+	}
+
+	failureIdx := strings.Index(output, `outcome="failure"`)
+	successIdx := strings.Index(output, `outcome="success"`)
+	if failureIdx == -1 || successIdx == -1 || failureIdx > successIdx {
+		t.Errorf("expected label combinations sorted by label value, got:\n%s", output)
+	}
+}
+
+func TestGaugeVecSetOverwrites(t *testing.T) {
+	registry := NewRegistry()
+	vec := registry.NewGaugeVec("tunnel_open", "Tunnel open state.", "endpoint_id")
+
+	vec.WithLabelValues("1").Set(1)
+	vec.WithLabelValues("1").Set(0)
+
+	var buf bytes.Buffer
+	registry.Expose(&buf)
+	output := buf.String()
+
+	if !strings.Contains(output, `tunnel_open{endpoint_id="1"} 0`) {
+		t.Errorf("expected overwritten value, got:\n%s", output)
+	}
+
+	if strings.Contains(output, `tunnel_open{endpoint_id="1"} 1`) {
+		t.Errorf("did not expect stale value in output:\n%s", output)
+	}
+}
+
+func TestHistogramVecBucketsAreCumulative(t *testing.T) {
+	registry := NewRegistry()
+	hist := registry.NewHistogramVecWithBuckets("call_duration_seconds", "Call duration.", []float64{1, 5}, "call")
+
+	h := hist.WithLabelValues("ImagePull")
+	h.Observe(0.5)
+	h.Observe(3)
+	h.Observe(10)
+
+	var buf bytes.Buffer
+	registry.Expose(&buf)
+	output := buf.String()
+
+	cases := []struct {
+		le   string
+		want string
+	}{
+		{`le="1"`, `call_duration_seconds_bucket{call="ImagePull",le="1"} 1`},
+		{`le="5"`, `call_duration_seconds_bucket{call="ImagePull",le="5"} 2`},
+		{`le="+Inf"`, `call_duration_seconds_bucket{call="ImagePull",le="+Inf"} 3`},
+	}
+
+	for _, c := range cases {
+		if !strings.Contains(output, c.want) {
+			t.Errorf("expected bucket sample %q, got:\n%s", c.want, output)
+		}
+	}
+
+	if !strings.Contains(output, `call_duration_seconds_sum{call="ImagePull"} 13.5`) {
+		t.Errorf("expected sum sample, got:\n%s", output)
+	}
+
+	if !strings.Contains(output, `call_duration_seconds_count{call="ImagePull"} 3`) {
+		t.Errorf("expected count sample, got:\n%s", output)
+	}
+}
+
+func TestRegisterPanicsOnDuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate metric name")
+		}
+	}()
+
+	registry := NewRegistry()
+	registry.NewCounter("duplicate", "first")
+	registry.NewGauge("duplicate", "second")
+}
+
+func TestNewSetsBuildInfoGauge(t *testing.T) {
+	m := New("2.19.0")
+
+	var buf bytes.Buffer
+	m.Registry.Expose(&buf)
+	output := buf.String()
+
+	if !strings.Contains(output, `agent_build_info{version="2.19.0"} 1`) {
+		t.Errorf("expected build_info sample for the configured version, got:\n%s", output)
+	}
+}
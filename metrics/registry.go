@@ -0,0 +1,100 @@
+package metrics
+
+// Metrics is the full set of instrumentation exposed by the agent on
+// /metrics. A single instance is created at startup and threaded through
+// pollServiceConfig, APIServerConfig and the docker package so every
+// component records into the same Registry.
+type Metrics struct {
+	Registry *Registry
+
+	PollAttemptsTotal   *CounterVec
+	PollDuration        *HistogramVec
+	PollIntervalSeconds *Gauge
+
+	TunnelOpen                *GaugeVec
+	TunnelBytesIn             *CounterVec
+	TunnelBytesOut            *CounterVec
+	TunnelLastActivitySeconds *GaugeVec
+
+	EdgeStackDeploysTotal *CounterVec
+
+	ScheduleExecutionDuration *HistogramVec
+
+	DockerCallDuration *HistogramVec
+
+	buildInfo *GaugeVec
+}
+
+// New creates a Metrics instance backed by a fresh Registry and sets the
+// build_info gauge using version.
+func New(version string) *Metrics {
+	registry := NewRegistry()
+
+	m := &Metrics{
+		Registry: registry,
+
+		PollAttemptsTotal: registry.NewCounterVec(
+			"agent_poll_attempts_total",
+			"Total number of Portainer poll attempts, partitioned by outcome.",
+			"outcome",
+		),
+		PollDuration: registry.NewHistogramVec(
+			"agent_poll_duration_seconds",
+			"Duration of Portainer poll requests in seconds, partitioned by outcome.",
+			"outcome",
+		),
+		PollIntervalSeconds: registry.NewGauge(
+			"agent_poll_interval_seconds",
+			"Current Portainer poll interval in seconds, including any backoff applied.",
+		),
+
+		TunnelOpen: registry.NewGaugeVec(
+			"agent_tunnel_open",
+			"Whether a reverse tunnel is currently open for the endpoint (1) or not (0).",
+			"endpoint_id",
+		),
+		TunnelBytesIn: registry.NewCounterVec(
+			"agent_tunnel_bytes_in_total",
+			"Total bytes received over the reverse tunnel, partitioned by endpoint.",
+			"endpoint_id",
+		),
+		TunnelBytesOut: registry.NewCounterVec(
+			"agent_tunnel_bytes_out_total",
+			"Total bytes sent over the reverse tunnel, partitioned by endpoint.",
+			"endpoint_id",
+		),
+		TunnelLastActivitySeconds: registry.NewGaugeVec(
+			"agent_tunnel_last_activity_seconds",
+			"Seconds since the last observed activity on the reverse tunnel, partitioned by endpoint.",
+			"endpoint_id",
+		),
+
+		EdgeStackDeploysTotal: registry.NewCounterVec(
+			"agent_edge_stack_deploys_total",
+			"Total number of Edge stack deployments, partitioned by stack ID and outcome.",
+			"stack_id", "outcome",
+		),
+
+		ScheduleExecutionDuration: registry.NewHistogramVec(
+			"agent_schedule_execution_duration_seconds",
+			"Duration of Edge schedule executions in seconds, partitioned by schedule ID.",
+			"schedule_id",
+		),
+
+		DockerCallDuration: registry.NewHistogramVec(
+			"agent_docker_call_duration_seconds",
+			"Duration of Docker API calls made by the agent in seconds, partitioned by call.",
+			"call",
+		),
+
+		buildInfo: registry.NewGaugeVec(
+			"agent_build_info",
+			"Always 1; labeled with the running agent version.",
+			"version",
+		),
+	}
+
+	m.buildInfo.WithLabelValues(version).Set(1)
+
+	return m
+}
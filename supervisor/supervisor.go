@@ -0,0 +1,124 @@
+// Package supervisor owns the agent's top-level lifecycle: it applies the
+// process-wide log level/format as the very first thing that happens,
+// installs a signal trap, cancels a root context on shutdown so in-flight
+// work across the poll loops, the API server and the Docker calls they
+// trigger can be cancelled instead of hanging, and then runs each
+// component's shutdown function in order within a bounded grace period.
+package supervisor
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/portainer/agent/logger"
+)
+
+var log = logger.New("supervisor")
+
+// ShutdownFunc is called during an orderly shutdown with a context bound by
+// the Supervisor's shutdown timeout. Components return the first error they
+// hit, if any; Shutdown still calls every remaining function.
+type ShutdownFunc func(ctx context.Context) error
+
+// Supervisor owns the agent's root context and coordinates graceful
+// shutdown across the components that register with it.
+type Supervisor struct {
+	shutdownTimeout time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+}
+
+// New returns a Supervisor with a fresh root context. shutdownTimeout bounds
+// how long Shutdown waits for all ShutdownFuncs to complete; it is wired
+// through the --shutdown-timeout flag. logLevel and logFormat are the raw
+// --log-level/--log-format flag values; New parses and applies them via
+// logger.Configure before anything else runs, since Supervisor.New is the
+// first thing main constructs.
+func New(shutdownTimeout time.Duration, logLevel, logFormat string) *Supervisor {
+	logger.Configure(logger.ParseLevel(logLevel), logger.ParseFormat(logFormat))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		shutdownTimeout: shutdownTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Context returns the root context. Components thread this through their
+// long-running loops so that cancelling it (on the first shutdown signal)
+// unblocks them without waiting for an explicit Stop call.
+func (s *Supervisor) Context() context.Context {
+	return s.ctx
+}
+
+// WaitForSignal blocks until SIGINT or SIGTERM is received, cancels the root
+// context, and returns. A SIGQUIT received at any point (including while
+// waiting for the initial signal) dumps all goroutine stacks to stderr for
+// debugging and does not by itself trigger shutdown. A second SIGINT/SIGTERM
+// received after the first forces an immediate process exit, in case a
+// component's shutdown hangs.
+func (s *Supervisor) WaitForSignal() os.Signal {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	for {
+		sig := <-sigCh
+		if sig == syscall.SIGQUIT {
+			dumpGoroutines()
+			continue
+		}
+
+		log.Info().Str("signal", sig.String()).Msg("received shutdown signal")
+		s.cancel()
+
+		go forceExitOnRepeatedSignal(sigCh)
+
+		return sig
+	}
+}
+
+func forceExitOnRepeatedSignal(sigCh <-chan os.Signal) {
+	for {
+		sig := <-sigCh
+		if sig == syscall.SIGQUIT {
+			dumpGoroutines()
+			continue
+		}
+
+		log.Warn().Str("signal", sig.String()).Msg("received second shutdown signal, forcing exit")
+		os.Exit(1)
+	}
+}
+
+func dumpGoroutines() {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	os.Stderr.Write(buf[:n])
+}
+
+// Shutdown runs every ShutdownFunc in order, within the Supervisor's
+// configured shutdown timeout, and returns the first error encountered.
+// Order matters: callers should list components so that upstream producers
+// of work (the API server accepting new requests, the poll loop creating
+// tunnels) are stopped before the resources they depend on are torn down.
+func (s *Supervisor) Shutdown(funcs ...ShutdownFunc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var firstErr error
+	for _, fn := range funcs {
+		if err := fn(ctx); err != nil {
+			log.Error().Err(err).Msg("error during component shutdown")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
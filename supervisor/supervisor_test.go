@@ -0,0 +1,66 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownRunsFuncsInOrder(t *testing.T) {
+	s := New(time.Second, "info", "console")
+
+	var order []int
+	record := func(n int) ShutdownFunc {
+		return func(ctx context.Context) error {
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	if err := s.Shutdown(record(1), record(2), record(3)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []int{1, 2, 3}
+	if len(order) != len(want) {
+		t.Fatalf("expected order %v, got %v", want, order)
+	}
+	for i, n := range want {
+		if order[i] != n {
+			t.Fatalf("expected order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestShutdownReturnsFirstErrorButRunsRemainingFuncs(t *testing.T) {
+	s := New(time.Second, "info", "console")
+
+	errFirst := errors.New("first component failed")
+	errSecond := errors.New("second component failed")
+
+	var ran []int
+	fn := func(n int, err error) ShutdownFunc {
+		return func(ctx context.Context) error {
+			ran = append(ran, n)
+			return err
+		}
+	}
+
+	err := s.Shutdown(fn(1, errFirst), fn(2, errSecond), fn(3, nil))
+	if !errors.Is(err, errFirst) {
+		t.Fatalf("expected first error %v, got %v", errFirst, err)
+	}
+
+	if len(ran) != 3 {
+		t.Fatalf("expected all three funcs to run despite the first error, got %v", ran)
+	}
+}
+
+func TestShutdownWithNoFuncsReturnsNoError(t *testing.T) {
+	s := New(time.Second, "info", "console")
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}